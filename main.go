@@ -1,137 +1,904 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"math/rand"
+	"os"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
 )
 
-func attack(charName, charClass string) string {
-	if charClass == "warrior" {
-		return fmt.Sprintf("%s нанес урон противнику равный %d.", charName, 5+randint(3, 5))
+// Базовые характеристики персонажа до выбора класса; по умолчанию их берёт Config.
+const (
+	BaseAttack  = 5
+	BaseDefense = 10
+	BaseStamina = 80
+)
+
+const (
+	uniformDistribution    = "uniform"
+	triangularDistribution = "triangular"
+)
+
+// Config содержит настройки игры, которые задаются при запуске.
+type Config struct {
+	// BaseAttack, BaseDefense и BaseStamina — стартовые характеристики персонажа.
+	BaseAttack  int
+	BaseDefense int
+	BaseStamina int
+	// Distribution — распределение случайных бросков: uniform или triangular.
+	Distribution string
+	// NameSeed включает режим, в котором броски зависят только от имени персонажа.
+	NameSeed bool
+	// Seed задаёт зерно случайных бросков; 0 означает случайное зерно.
+	Seed int64
+	// VoiceLines добавляет к результатам команд реплики персонажа.
+	VoiceLines bool
+}
+
+var config = Config{
+	BaseAttack:   BaseAttack,
+	BaseDefense:  BaseDefense,
+	BaseStamina:  BaseStamina,
+	Distribution: uniformDistribution,
+	VoiceLines:   true,
+}
+
+// bindConfigFlags связывает поля c с флагами fs. Значения, уже записанные
+// в c, становятся значениями флагов по умолчанию, поэтому флаг, заданный
+// при запуске, переопределяет настройку, а незаданный оставляет её как есть.
+func bindConfigFlags(fs *flag.FlagSet, c *Config) {
+	fs.IntVar(&c.BaseAttack, "base-attack", c.BaseAttack, "базовая атака персонажа")
+	fs.IntVar(&c.BaseDefense, "base-defense", c.BaseDefense, "базовая защита персонажа")
+	fs.IntVar(&c.BaseStamina, "base-stamina", c.BaseStamina, "базовая выносливость персонажа")
+	fs.StringVar(&c.Distribution, "dist", c.Distribution, "распределение случайных бросков: uniform или triangular")
+	fs.BoolVar(&c.NameSeed, "name-seed", c.NameSeed, "выводить зерно случайных бросков из имени персонажа")
+	fs.BoolVar(&c.VoiceLines, "voice", c.VoiceLines, "добавлять к результатам команд реплики персонажа")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "зерно случайных бросков, 0 — случайное")
+}
+
+// validateConfig проверяет настройки, заданные при запуске.
+func validateConfig(c Config) error {
+	if c.BaseAttack < 0 || c.BaseDefense < 0 || c.BaseStamina < 0 {
+		return errors.New("базовые характеристики не могут быть отрицательными")
+	}
+	// Броски класса прибавляются к базовым значениям, поэтому база должна
+	// перекрывать самый низкий бросок любого класса.
+	for _, class := range charClasses {
+		if c.BaseAttack+class.damage.min < 0 {
+			return fmt.Errorf("базовая атака должна быть не меньше %d: иначе урон класса %s станет отрицательным", -class.damage.min, class.title)
+		}
+		if c.BaseDefense+class.defence.min < 0 {
+			return fmt.Errorf("базовая защита должна быть не меньше %d: иначе защита класса %s станет отрицательной", -class.defence.min, class.title)
+		}
 	}
+	if c.Distribution != uniformDistribution && c.Distribution != triangularDistribution {
+		return fmt.Errorf("неизвестное распределение %q", c.Distribution)
+	}
+	if c.NameSeed && c.Seed != 0 {
+		return fmt.Errorf("флаги -seed и -name-seed нельзя задавать вместе")
+	}
+	return nil
+}
 
-	if charClass == "mage" {
-		return fmt.Sprintf("%s нанес урон противнику равный %d.", charName, 5+randint(5, 10))
+// attack возвращает сообщение об атаке и нанесённый урон.
+func attack(charName, charClass string) (string, int) {
+	minDamage, maxDamage, ok := ClassDamageRange(charClass)
+	if !ok {
+		return "неизвестный класс персонажа", 0
 	}
+	damage := config.BaseAttack + randint(minDamage, maxDamage)
+	return fmt.Sprintf("%s нанес урон противнику равный %d.", charName, damage), damage
+}
 
-	if charClass == "healer" {
-		return fmt.Sprintf("%s нанес урон противнику равный %d.", charName, 5+randint(-3, -1))
+// defence возвращает сообщение о защите и заблокированный урон.
+func defence(charName, charClass string) (string, int) {
+	minDefence, maxDefence, ok := ClassDefenseRange(charClass)
+	if !ok {
+		return "неизвестный класс персонажа", 0
 	}
+	blocked := config.BaseDefense + randint(minDefence, maxDefence)
+	return fmt.Sprintf("%s блокировал %d урона.", charName, blocked), blocked
+}
 
-	return "неизвестный класс персонажа"
+// actionStats накапливает результаты действия, которое отрабатывается в focusTraining.
+type actionStats struct {
+	count, total, min, max int
 }
 
-// обратите внимание на "if else" и на "else"
-func defence(charName, charClass string) string {
-	switch charClass {
-	case "warrior":
-		return fmt.Sprintf("%s блокировал %d урона.", charName, 10+randint(5, 10))
-	case "mage":
-		return fmt.Sprintf("%s блокировал %d урона.", charName, 10+randint(-2, 2))
-	case "healer":
-		return fmt.Sprintf("%s блокировал %d урона.", charClass, 10+randint(2, 5))
-	default:
-		return "неизвестный класс персонажа"
+func (s *actionStats) add(value int) {
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	s.count++
+	s.total += value
+}
+
+// summary описывает результаты действия или возвращает пустую строку,
+// если действие ни разу не применялось.
+func (s actionStats) summary(title string) string {
+	if s.count == 0 {
+		return ""
 	}
+	average := float64(s.total) / float64(s.count)
+	return fmt.Sprintf("%s — попыток: %d, в среднем %.1f, минимум %d, максимум %d.", title, s.count, average, s.min, s.max)
 }
 
-// обратите внимание на "if else" и на "else"
+// special возвращает сообщение о специальном умении класса.
 func special(charName, charClass string) string {
-	switch charClass {
-	case "warrior":
-		return fmt.Sprintf("%s применил специальное умение `Выносливость %d`", charName, 80+25)
-	case "mage":
-		return fmt.Sprintf("%s применил специальное умение `Атака %d`", charName, 5+40)
-	case "healer":
-		return fmt.Sprintf("%s применил специальное умение `Защита %d`", charName, 10+30)
-	default:
+	class, ok := charClassByName(charClass)
+	if !ok {
 		return "неизвестный класс персонажа"
 	}
+	return fmt.Sprintf("%s применил специальное умение `%s %d`", charName, class.special.stat, baseStat(class.special.stat)+class.special.bonus)
+}
+
+// Характеристики, которые может усилить специальное умение.
+const (
+	statStamina = "Выносливость"
+	statAttack  = "Атака"
+	statDefense = "Защита"
+)
+
+// baseStat возвращает базовое значение характеристики из настроек.
+func baseStat(stat string) int {
+	switch stat {
+	case statStamina:
+		return config.BaseStamina
+	case statAttack:
+		return config.BaseAttack
+	case statDefense:
+		return config.BaseDefense
+	default:
+		return 0
+	}
+}
+
+// Prompts содержит приглашения к вводу, чтобы их можно было переопределить или перевести.
+// Каждое приглашение выводится как есть, вместе с завершающим ": ".
+type Prompts struct {
+	Name string
+	// Class выводится перед списком классов, который добавляет classPrompt.
+	Class   string
+	Confirm string
+	Command string
+	// Focus спрашивает, какое действие отработать, FocusRepeat — повторить ли его ещё раз.
+	Focus       string
+	FocusRepeat string
+}
+
+var prompts = Prompts{
+	Name:        "...назови себя: ",
+	Class:       "Введи название персонажа, за которого хочешь играть: ",
+	Confirm:     "Нажми (Y) или введи «да», чтобы подтвердить выбор, или любую другую кнопку, чтобы выбрать другого персонажа: ",
+	Command:     "Введи команду: ",
+	Focus:       "Какое действие отработать, attack или defence: ",
+	FocusRepeat: "Нажми Enter, чтобы повторить, или введи skip, чтобы закончить отработку: ",
+}
+
+var reader = bufio.NewScanner(os.Stdin)
+
+// output — куда игра пишет весь свой вывод. Ошибки запуска main, как и
+// пакет flag, пишет в os.Stderr, чтобы они не смешивались с диалогом.
+var output io.Writer = os.Stdout
+
+// interactive сообщает, вводит ли команды человек за терминалом.
+var interactive = isInteractive(os.Stdin)
+
+// isInteractive проверяет, что r — терминал, а не файл или канал.
+func isInteractive(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// setInput переключает чтение команд на r, например на файл сценария.
+func setInput(r io.Reader) {
+	reader = bufio.NewScanner(r)
+	interactive = isInteractive(r)
+}
+
+// normalizeInput обрезает строку и заменяет каждую последовательность
+// пробельных символов Unicode, включая неразрывный пробел, одним пробелом.
+func normalizeInput(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// readInput выводит приглашение и считывает строку, введённую игроком.
+// Если ввод не с терминала, прочитанная строка повторяется в выводе,
+// чтобы он читался как диалог. Когда ввод закончился, возвращает io.EOF,
+// а если строку прочитать не удалось — ошибку чтения.
+func readInput(prompt string) (string, error) {
+	fmt.Fprint(output, prompt)
+	if !reader.Scan() {
+		fmt.Fprintln(output)
+		if err := reader.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	input := normalizeInput(reader.Text())
+	if !interactive {
+		fmt.Fprintln(output, input)
+	}
+	return input, nil
+}
+
+// command описывает команду, все варианты её ввода и строку подсказки.
+type command struct {
+	name        string
+	aliases     []string
+	description string
+}
+
+var trainingCommands = []command{
+	{name: "attack", aliases: []string{"1", "атака"}, description: "чтобы атаковать противника"},
+	{name: "defence", aliases: []string{"2", "защита"}, description: "чтобы блокировать атаку противника"},
+	{name: "special", aliases: []string{"3", "умение"}, description: "чтобы использовать свою суперсилу"},
+	{name: "skip", aliases: []string{"4", "пропустить"}, description: "если не хочешь тренироваться"},
+	{name: "seed", aliases: []string{"fight", "зерно"}, description: "чтобы узнать зерно тренировки"},
+	{name: "again", aliases: []string{".", "ещё"}, description: "чтобы повторить последнее действие"},
+	{name: "focus", aliases: []string{"отработка"}, description: "чтобы отработать одно действие и узнать разброс результатов"},
+	{name: "classes", aliases: []string{"классы"}, description: "чтобы сравнить классы"},
+}
+
+// commandsHelp перечисляет команды вместе с синонимами и подсказками,
+// по строке на команду.
+func commandsHelp(commands []command) string {
+	var b strings.Builder
+	for _, cmd := range commands {
+		b.WriteString(cmd.name)
+		if len(cmd.aliases) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(cmd.aliases, ", "))
+		}
+		fmt.Fprintf(&b, " — %s\n", cmd.description)
+	}
+	return b.String()
+}
+
+// resolveCommand возвращает имя команды по номеру, имени или синониму без учёта регистра.
+func resolveCommand(input string, commands []command) (string, bool) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	for _, cmd := range commands {
+		if input == cmd.name {
+			return cmd.name, true
+		}
+		for _, alias := range cmd.aliases {
+			if input == alias {
+				return cmd.name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// repeatInputToken повторяет предыдущую команду. Он разбирается до
+// resolveCommand, поэтому не может быть ни именем, ни синонимом команды.
+const repeatInputToken = "!!"
+
+// validateCommands проверяет, что имена и синонимы команд не повторяются
+// и не пересекаются: иначе одна команда молча перекрыла бы другую. Кроме
+// того, они должны быть записаны строчными буквами, ведь resolveCommand
+// сравнивает их с вводом, приведённым к нижнему регистру.
+func validateCommands(commands []command) error {
+	owners := make(map[string]string)
+	for _, cmd := range commands {
+		if err := validateCommandWord(cmd.name); err != nil {
+			return err
+		}
+		if _, ok := owners[cmd.name]; ok {
+			return fmt.Errorf("команда %q зарегистрирована дважды", cmd.name)
+		}
+		owners[cmd.name] = cmd.name
+	}
+	for _, cmd := range commands {
+		for _, alias := range cmd.aliases {
+			if err := validateCommandWord(alias); err != nil {
+				return err
+			}
+			if owner, ok := owners[alias]; ok {
+				return fmt.Errorf("синоним %q команды %q уже занят командой %q", alias, cmd.name, owner)
+			}
+			owners[alias] = cmd.name
+		}
+	}
+	return nil
+}
+
+// validateCommandWord проверяет одно имя или синоним команды.
+func validateCommandWord(word string) error {
+	if word == repeatInputToken {
+		return fmt.Errorf("%q зарезервировано для повтора предыдущей команды", word)
+	}
+	if strings.ToLower(word) != word {
+		return fmt.Errorf("команда %q должна быть записана строчными буквами", word)
+	}
+	return nil
 }
 
 // здесь обратите внимание на имена параметров
-func startTraining(charName, charClass string) string {
+func startTraining(charName, charClass string) (string, error) {
 	if charClass == "warrior" {
-		fmt.Printf("%s, ты Воитель - отличный боец ближнего боя.\n", charName)
+		fmt.Fprintf(output, "%s, ты Воитель - отличный боец ближнего боя.\n", charName)
 	}
 
 	if charClass == "mage" {
-		fmt.Printf("%s, ты Маг - превосходный укротитель стихий.\n", charName)
+		fmt.Fprintf(output, "%s, ты Маг - превосходный укротитель стихий.\n", charName)
 	}
 
 	if charClass == "healer" {
-		fmt.Printf("%s, ты Лекарь - чародей, способный исцелять раны.\n", charName)
+		fmt.Fprintf(output, "%s, ты Лекарь - чародей, способный исцелять раны.\n", charName)
 	}
 
-	fmt.Println("Потренируйся управлять своими навыками.")
-	fmt.Println("Введи одну из команд: attack — чтобы атаковать противника,")
-	fmt.Println("defence — чтобы блокировать атаку противника,")
-	fmt.Println("special — чтобы использовать свою суперсилу.")
-	fmt.Println("Если не хочешь тренироваться, введи команду skip.")
+	fmt.Fprintln(output, "Потренируйся управлять своими навыками.")
+	fmt.Fprintln(output, "Введи одну из команд или её синоним в скобках:")
+	fmt.Fprint(output, commandsHelp(trainingCommands))
+	fmt.Fprintf(output, "Набери %s, чтобы ещё раз ввести предыдущую команду.\n", repeatInputToken)
+	fmt.Fprintln(output, seedMessage())
 
-	var cmd string
+	var cmd, lastAction string
+	var history []string
 	for cmd != "skip" {
-		fmt.Print("Введи команду: ")
-		fmt.Scanf("%s\n", &cmd)
+		input, err := readInput(prompts.Command)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if input == "" {
+			continue
+		}
+
+		if input == repeatInputToken {
+			if len(history) == 0 {
+				fmt.Fprintln(output, "история команд пока пуста")
+				continue
+			}
+			input = history[len(history)-1]
+			fmt.Fprintln(output, input)
+		}
+
+		var ok bool
+		cmd, ok = resolveCommand(input, trainingCommands)
+		if !ok {
+			fmt.Fprintln(output, "неизвестная команда")
+			continue
+		}
+		history = append(history, input)
+
+		if cmd == "again" {
+			if lastAction == "" {
+				fmt.Fprintln(output, "пока нечего повторять: сначала введи attack, defence или special")
+				continue
+			}
+			cmd = lastAction
+		}
 
 		if cmd == "attack" {
-			fmt.Println(attack(charName, charClass))
+			result, _ := attack(charName, charClass)
+			fmt.Fprintln(output, withVoiceLine(result, charClass, cmd))
 		}
 
 		if cmd == "defence" {
-			fmt.Println(defence(charName, charClass))
+			result, _ := defence(charName, charClass)
+			fmt.Fprintln(output, withVoiceLine(result, charClass, cmd))
 		}
 
 		if cmd == "special" {
-			fmt.Println(special(charName, charClass))
+			fmt.Fprintln(output, withVoiceLine(special(charName, charClass), charClass, cmd))
+		}
+
+		if cmd == "seed" {
+			fmt.Fprintln(output, seedMessage())
+		}
+
+		if cmd == "classes" {
+			fmt.Fprint(output, classesTable())
+		}
+
+		if cmd == "focus" {
+			err := focusTraining(charName, charClass)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if cmd == "attack" || cmd == "defence" || cmd == "special" {
+			lastAction = cmd
 		}
 	}
 
-	return "тренировка окончена"
+	return "тренировка окончена", nil
+}
+
+// focusTraining повторяет выбранное действие, пока игрок нажимает Enter,
+// а после skip выводит статистику его результатов. Если ввод закончился
+// или не читается, статистика тоже выводится, а ошибка возвращается.
+func focusTraining(charName, charClass string) error {
+	input, err := readInput(prompts.Focus)
+	if err != nil {
+		return err
+	}
+
+	action, _ := resolveCommand(input, trainingCommands)
+	var roll func(charName, charClass string) (string, int)
+	var title string
+	switch action {
+	case "attack":
+		roll, title = attack, "Урон"
+	case "defence":
+		roll, title = defence, "Блок"
+	default:
+		fmt.Fprintln(output, "отработать можно только attack или defence")
+		return nil
+	}
+
+	var stats actionStats
+	input = ""
+	for {
+		if input == "" {
+			result, value := roll(charName, charClass)
+			stats.add(value)
+			fmt.Fprintln(output, withVoiceLine(result, charClass, action))
+		} else if cmd, _ := resolveCommand(input, trainingCommands); cmd == "skip" {
+			break
+		} else {
+			fmt.Fprintln(output, "неизвестная команда")
+		}
+
+		input, err = readInput(prompts.FocusRepeat)
+		if err != nil {
+			break
+		}
+	}
+	fmt.Fprintln(output, stats.summary(title))
+	return err
+}
+
+// rollRange — границы броска randint: min входит в диапазон, max нет.
+type rollRange struct {
+	min, max int
+}
+
+// specialInfo описывает специальное умение: какую характеристику оно усиливает и на сколько.
+type specialInfo struct {
+	stat  string
+	bonus int
+}
+
+// charClassInfo описывает класс персонажа; порядок в charClasses задаёт его номер при выборе.
+type charClassInfo struct {
+	name        string
+	title       string
+	description string
+	// damage и defence — границы бросков, которые прибавляются к базовым атаке и защите.
+	damage  rollRange
+	defence rollRange
+	special specialInfo
+	// voiceLines — реплики персонажа, которые добавляются к результату команды.
+	voiceLines map[string]string
+}
+
+var charClasses = []charClassInfo{
+	{
+		name:        "warrior",
+		title:       "Воитель",
+		description: "Воитель — дерзкий воин ближнего боя. Сильный, выносливый и отважный.",
+		damage:      rollRange{min: 3, max: 5},
+		defence:     rollRange{min: 5, max: 10},
+		special:     specialInfo{stat: statStamina, bonus: 25},
+		voiceLines: map[string]string{
+			"attack":  "За славу!",
+			"defence": "Не пройдёшь!",
+			"special": "Меня не сломить!",
+		},
+	},
+	{
+		name:        "mage",
+		title:       "Маг",
+		description: "Маг — находчивый воин дальнего боя. Обладает высоким интеллектом.",
+		damage:      rollRange{min: 5, max: 10},
+		defence:     rollRange{min: -2, max: 2},
+		special:     specialInfo{stat: statAttack, bonus: 40},
+		voiceLines: map[string]string{
+			"attack":  "Стихии, повинуйтесь!",
+			"defence": "Щит стихий!",
+			"special": "Узри истинную мощь!",
+		},
+	},
+	{
+		name:        "healer",
+		title:       "Лекарь",
+		description: "Лекарь — могущественный заклинатель. Черпает силы из природы, веры и духов.",
+		damage:      rollRange{min: -3, max: -1},
+		defence:     rollRange{min: 2, max: 5},
+		special:     specialInfo{stat: statDefense, bonus: 30},
+		voiceLines: map[string]string{
+			"attack":  "Прости, так нужно.",
+			"defence": "Свет, защити меня!",
+			"special": "Да хранят нас духи!",
+		},
+	},
+}
+
+// findCharClass ищет класс по имени или по номеру в списке, начиная с единицы.
+func findCharClass(input string) (charClassInfo, bool) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	for i, class := range charClasses {
+		if input == class.name || input == strconv.Itoa(i+1) {
+			return class, true
+		}
+	}
+	return charClassInfo{}, false
+}
+
+// classPrompt дополняет prompts.Class списком классов из charClasses
+// с теми номерами, которые принимает findCharClass.
+func classPrompt() string {
+	options := make([]string, 0, len(charClasses))
+	for i, class := range charClasses {
+		options = append(options, fmt.Sprintf("%s — %s (%d)", class.title, class.name, i+1))
+	}
+	return prompts.Class + strings.Join(options, ", ") + ": "
+}
+
+// classTitles возвращает названия классов через запятую в порядке charClasses.
+func classTitles() string {
+	titles := make([]string, 0, len(charClasses))
+	for _, class := range charClasses {
+		titles = append(titles, class.title)
+	}
+	return strings.Join(titles, ", ")
+}
+
+// confirmAnswers — ответы, которыми игрок подтверждает выбор. Чтобы
+// перевести игру, достаточно дополнить этот список.
+var confirmAnswers = []string{"y", "yes", "да", "д"}
+
+// isConfirmed сообщает, подтверждает ли ответ выбор, без учёта регистра.
+func isConfirmed(answer string) bool {
+	answer = strings.ToLower(answer)
+	for _, confirm := range confirmAnswers {
+		if answer == confirm {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCharClasses проверяет, что есть хотя бы один класс:
+// без них выбор персонажа отвергал бы любой ввод бесконечно.
+func validateCharClasses(classes []charClassInfo) error {
+	if len(classes) == 0 {
+		return errors.New("не задано ни одного класса персонажа")
+	}
+	return nil
+}
+
+// charClassByName ищет класс по его точному имени.
+func charClassByName(name string) (charClassInfo, bool) {
+	for _, class := range charClasses {
+		if class.name == name {
+			return class, true
+		}
+	}
+	return charClassInfo{}, false
+}
+
+// ClassDamageRange возвращает границы броска урона класса в формате randint.
+// ok == false, если такого класса нет.
+func ClassDamageRange(charClass string) (min, max int, ok bool) {
+	class, ok := charClassByName(charClass)
+	if !ok {
+		return 0, 0, false
+	}
+	return class.damage.min, class.damage.max, true
+}
+
+// ClassDefenseRange возвращает границы броска защиты класса в формате randint.
+// ok == false, если такого класса нет.
+func ClassDefenseRange(charClass string) (min, max int, ok bool) {
+	class, ok := charClassByName(charClass)
+	if !ok {
+		return 0, 0, false
+	}
+	return class.defence.min, class.defence.max, true
+}
+
+// classesTable перечисляет все классы из charClasses с итоговыми
+// диапазонами урона и защиты и бонусом специального умения.
+func classesTable() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Класс\tУрон\tЗащита\tУмение\tОписание")
+	for _, class := range charClasses {
+		fmt.Fprintf(w, "%s\t%d–%d\t%d–%d\t%s +%d\t%s\n",
+			class.name,
+			config.BaseAttack+class.damage.min, config.BaseAttack+class.damage.max-1,
+			config.BaseDefense+class.defence.min, config.BaseDefense+class.defence.max-1,
+			class.special.stat, class.special.bonus,
+			class.description)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// withVoiceLine дополняет результат команды репликой персонажа, если реплики включены.
+func withVoiceLine(result, charClass, cmd string) string {
+	class, ok := charClassByName(charClass)
+	if !config.VoiceLines || !ok {
+		return result
+	}
+	line, ok := class.voiceLines[cmd]
+	if !ok {
+		return result
+	}
+	return result + " " + line
 }
 
 // обратите внимание на имя функции и имена переменных
-func chooseCharClass() string {
+func chooseCharClass() (string, error) {
 	var approveChoice string
 	var charClass string
 
-	for approveChoice != "y" {
-		fmt.Print("Введи название персонажа, за которого хочешь играть: Воитель — warrior, Маг — mage, Лекарь — healer: ")
-		fmt.Scanf("%s\n", &charClass)
-		if charClass == "warrior" {
-			fmt.Println("Воитель — дерзкий воин ближнего боя. Сильный, выносливый и отважный.")
-		} else if charClass == "mage" {
-			fmt.Println("Маг — находчивый воин дальнего боя. Обладает высоким интеллектом.")
-		} else if charClass == "healer" {
-			fmt.Println("Лекарь — могущественный заклинатель. Черпает силы из природы, веры и духов.")
+	for !isConfirmed(approveChoice) {
+		input, err := readInput(classPrompt())
+		if err != nil {
+			return "", err
+		}
+		class, ok := findCharClass(input)
+		if !ok {
+			fmt.Fprintln(output, "неизвестный класс персонажа")
+			continue
+		}
+		charClass = class.name
+		fmt.Fprintln(output, class.description)
+
+		approveChoice, err = readInput(prompts.Confirm)
+		if err != nil {
+			return "", err
+		}
+	}
+	return charClass, nil
+}
+
+// charNameRule — правило для имён, которое видит игрок. Эмодзи запрещены,
+// потому что их ширина в терминале зависит от шрифта и ломает вывод.
+const charNameRule = "имя может состоять только из букв, цифр, пробелов, дефисов и апострофов, эмодзи не допускаются"
+
+// validateCharName убирает из имени невидимые символы нулевой ширины и
+// проверяет остальные по charNameRule.
+func validateCharName(charName string) (string, error) {
+	charName = normalizeInput(strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, charName))
+	if charName == "" {
+		return "", errors.New("имя не может быть пустым")
+	}
+	for _, r := range charName {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) && !strings.ContainsRune(" -'", r) {
+			return "", fmt.Errorf("недопустимый символ %q: %s", r, charNameRule)
+		}
+	}
+	return charName, nil
+}
+
+// readCharName запрашивает имя, пока игрок не введёт допустимое.
+// Ошибку возвращает, только если ввод закончился или не читается.
+func readCharName() (string, error) {
+	for {
+		input, err := readInput(prompts.Name)
+		if err != nil {
+			return "", err
 		}
-		fmt.Print("Нажми (Y), чтобы подтвердить выбор, или любую другую кнопку, чтобы выбрать другого персонажа: ")
-		fmt.Scanf("%s\n", &approveChoice)
-		approveChoice = strings.ToLower(approveChoice)
+		charName, err := validateCharName(input)
+		if err == nil {
+			return charName, nil
+		}
+		fmt.Fprintln(output, err)
 	}
-	return charClass
+}
+
+const inputEndedMessage = "Ввод закончился до начала тренировки, игра завершена."
+
+// play проводит игру от приветствия до конца тренировки. Возвращает io.EOF,
+// если ввод закончился раньше, чем началась тренировка, или ошибку чтения.
+func play() error {
+	fmt.Fprintln(output, "Приветствую тебя, искатель приключений!")
+	fmt.Fprintln(output, "Прежде чем начать игру...")
+
+	charName, err := readCharName()
+	if err != nil {
+		return stopPlay(err)
+	}
+
+	if config.NameSeed {
+		setSeed(SeedFromName(charName))
+	}
+
+	fmt.Fprintf(output, "Здравствуй, %s\n", charName)
+	fmt.Fprintf(output, "Сейчас твоя выносливость — %d, атака — %d и защита — %d.\n", config.BaseStamina, config.BaseAttack, config.BaseDefense)
+	fmt.Fprintln(output, "Ты можешь выбрать один из путей силы:")
+	fmt.Fprintln(output, classTitles())
+
+	char_class, err := chooseCharClass()
+	if err != nil {
+		return stopPlay(err)
+	}
+
+	result, err := startTraining(charName, char_class)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(output, result)
+	return nil
+}
+
+// stopPlay сообщает игроку, что ввод закончился до начала тренировки,
+// и возвращает err дальше.
+func stopPlay(err error) error {
+	if errors.Is(err, io.EOF) {
+		fmt.Fprintln(output, inputEndedMessage)
+	}
+	return err
+}
+
+// demoScript — ввод демонстрационной игры: создание персонажа и короткая тренировка.
+const demoScript = "Демо\nwarrior\ny\nattack\ndefence\nspecial\nskip\n"
+
+// demoSeed фиксирует броски, чтобы вывод демонстрации не менялся от запуска к запуску.
+const demoSeed = 1
+
+// RunScripted проигрывает игру с зерном seed, читая команды из in и
+// печатая вывод в out. После игры прежние ввод, вывод и генератор
+// случайных бросков восстанавливаются.
+func RunScripted(s int64, in io.Reader, out io.Writer) error {
+	savedReader, savedInteractive, savedOutput := reader, interactive, output
+	savedSeed, savedRandom := seed, random
+	defer func() {
+		reader, interactive, output = savedReader, savedInteractive, savedOutput
+		seed, random = savedSeed, savedRandom
+	}()
+
+	setSeed(s)
+	setInput(in)
+	output = out
+	return play()
+}
+
+// Demo проигрывает игру по встроенному сценарию, не читая стандартный ввод.
+func Demo() error {
+	err := RunScripted(demoSeed, strings.NewReader(demoScript), output)
+	if errors.Is(err, io.EOF) {
+		return errors.New("демонстрация прервана: сценарий закончился раньше времени")
+	}
+	return err
+}
+
+// validateDemoFlags проверяет, что вместе с -demo не заданы флаги, которые
+// демонстрация не учитывает: она всегда играет свой сценарий со своим зерном.
+func validateDemoFlags(c Config, scriptPath string) error {
+	if c.Seed != 0 || c.NameSeed || scriptPath != "" {
+		return errors.New("флаг -demo нельзя сочетать с -seed, -name-seed и -script")
+	}
+	return nil
 }
 
 // обратите внимание на имена переменных
 func main() {
-	fmt.Println("Приветствую тебя, искатель приключений!")
-	fmt.Println("Прежде чем начать игру...")
+	bindConfigFlags(flag.CommandLine, &config)
+	demo := flag.Bool("demo", false, "проиграть демонстрационную игру без ввода")
+	scriptPath := flag.String("script", "", "файл с командами, которые читаются вместо стандартного ввода")
+	flag.Parse()
+
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := validateCommands(trainingCommands); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := validateCharClasses(charClasses); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *demo {
+		if err := validateDemoFlags(config, *scriptPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := Demo(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	var charName string
-	fmt.Print("...назови себя: ")
-	fmt.Scanf("%s\n", &charName)
+	if config.Seed != 0 {
+		setSeed(config.Seed)
+	}
 
-	fmt.Printf("Здравствуй, %s\n", charName)
-	fmt.Println("Сейчас твоя выносливость — 80, атака — 5 и защита — 10.")
-	fmt.Println("Ты можешь выбрать один из трёх путей силы:")
-	fmt.Println("Воитель, Маг, Лекарь")
+	if *scriptPath != "" {
+		script, err := os.Open(*scriptPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "не удалось открыть сценарий: %v\n", err)
+			os.Exit(1)
+		}
+		defer script.Close()
+		setInput(script)
+	}
+
+	if err := play(); err != nil && !errors.Is(err, io.EOF) {
+		fmt.Fprintf(os.Stderr, "не удалось прочитать ввод: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-	char_class := chooseCharClass()
+// seed — зерно, которым инициализирован random. Запуск с тем же зерном
+// и тем же вводом повторяет тренировку.
+var seed = time.Now().UnixNano()
 
-	fmt.Println(startTraining(charName, char_class))
+var random = rand.New(rand.NewSource(seed))
+
+// seedMessage сообщает текущее зерно и как повторить тренировку с ним.
+func seedMessage() string {
+	return fmt.Sprintf("Зерно тренировки: %d. Чтобы повторить её, запусти игру с -seed=%d.", seed, seed)
+}
+
+// setSeed заново инициализирует генератор случайных бросков.
+func setSeed(s int64) {
+	seed = s
+	random = rand.New(rand.NewSource(s))
 }
 
+// SeedFromName выводит зерно генератора из имени персонажа через FNV-1a,
+// поэтому одно и то же имя на любой платформе даёт одни и те же броски.
+func SeedFromName(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// randint возвращает случайное число из [min, max). При треугольном
+// распределении значения у середины диапазона выпадают чаще крайних.
+//
+// Это единственное место, где игра тратит random. Каждая атака или защита,
+// в том числе повтор в режиме focus, вызывает randint один раз: в
+// равномерном режиме это один Intn, в треугольном два Float64. Остальные
+// команды ничего не бросают.
+// Поэтому зерно и ввод целиком определяют вывод игры.
 func randint(min, max int) int {
-	return rand.Intn(max-min) + min
+	if config.Distribution == triangularDistribution {
+		x := (random.Float64() + random.Float64()) / 2
+		return int(x*float64(max-min)) + min
+	}
+	return random.Intn(max-min) + min
 }
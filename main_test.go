@@ -0,0 +1,812 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveCommand(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{input: "1", want: "attack", ok: true},
+		{input: "атака", want: "attack", ok: true},
+		{input: "ATTACK", want: "attack", ok: true},
+		{input: "\u00a0атака\u00a0", want: "attack", ok: true},
+		{input: "2", want: "defence", ok: true},
+		{input: "Умение", want: "special", ok: true},
+		{input: "пропустить", want: "skip", ok: true},
+		{input: "fly", want: "", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := resolveCommand(tt.input, trainingCommands)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("resolveCommand(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "атака", want: "атака"},
+		{input: "\u00a0атака\u00a0", want: "атака"},
+		{input: "ещё\u00a0\u00a0раз", want: "ещё раз"},
+		{input: "ещё\t \tраз", want: "ещё раз"},
+		{input: "\u3000умение\u3000", want: "умение"},
+		{input: "\u00a0\t\u3000", want: ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeInput(tt.input); got != tt.want {
+			t.Errorf("normalizeInput(%q) = %q; want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCommandsHelpListsEveryAlias(t *testing.T) {
+	help := commandsHelp(trainingCommands)
+	for _, cmd := range trainingCommands {
+		for _, word := range append([]string{cmd.name, cmd.description}, cmd.aliases...) {
+			if !strings.Contains(help, word) {
+				t.Errorf("подсказка не содержит %q:\n%s", word, help)
+			}
+		}
+	}
+}
+
+func TestFindCharClassByNumber(t *testing.T) {
+	for i, class := range charClasses {
+		number := strconv.Itoa(i + 1)
+		got, ok := findCharClass(number)
+		if !ok || got.name != class.name {
+			t.Errorf("findCharClass(%q) = %q, %v; want %q", number, got.name, ok, class.name)
+		}
+		option := fmt.Sprintf("%s (%s)", class.name, number)
+		if !strings.Contains(classPrompt(), option) {
+			t.Errorf("приглашение %q не содержит %q", classPrompt(), option)
+		}
+	}
+	for _, input := range []string{"0", strconv.Itoa(len(charClasses) + 1), "-1"} {
+		if _, ok := findCharClass(input); ok {
+			t.Errorf("findCharClass(%q) нашёл класс, а не должен был", input)
+		}
+	}
+}
+
+// withConfig подменяет настройки игры на время теста.
+func withConfig(t *testing.T, c Config) {
+	t.Helper()
+	saved := config
+	config = c
+	t.Cleanup(func() { config = saved })
+}
+
+func TestRandintDistribution(t *testing.T) {
+	if config.Distribution != uniformDistribution {
+		t.Fatalf("распределение по умолчанию %q, ожидалось %q", config.Distribution, uniformDistribution)
+	}
+
+	const samples = 100000
+	roll := func(distribution string) []int {
+		c := config
+		c.Distribution = distribution
+		withConfig(t, c)
+		setSeed(1)
+		counts := make([]int, 10)
+		for i := 0; i < samples; i++ {
+			counts[randint(0, 10)]++
+		}
+		return counts
+	}
+
+	triangular := roll(triangularDistribution)
+	if triangular[4] < 4*triangular[0] || triangular[5] < 4*triangular[9] {
+		t.Errorf("в треугольном распределении середина выпадает не чаще краёв: %v", triangular)
+	}
+
+	uniform := roll(uniformDistribution)
+	for value, n := range uniform {
+		if n < samples/10*9/10 || n > samples/10*11/10 {
+			t.Errorf("в равномерном распределении %d выпало %d раз из %d: %v", value, n, samples, uniform)
+		}
+	}
+
+	setSeed(7)
+	want := rand.New(rand.NewSource(7))
+	for i := 0; i < 100; i++ {
+		if got, w := randint(-3, 5), want.Intn(8)-3; got != w {
+			t.Fatalf("бросок %d: randint = %d, rand.Intn = %d", i, got, w)
+		}
+	}
+}
+
+// runScript проигрывает игру с зерном seed, подавая script вместо
+// стандартного ввода, и возвращает весь её вывод.
+func runScript(t *testing.T, seed int64, script string) (string, error) {
+	t.Helper()
+	var out strings.Builder
+	err := RunScripted(seed, strings.NewReader(script), &out)
+	return out.String(), err
+}
+
+func TestPlayStopsWhenInputEndsBeforeTraining(t *testing.T) {
+	out, err := runScript(t, 1, "Bob\n2\n")
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("play() = %v, want io.EOF", err)
+	}
+	if !strings.HasSuffix(out, inputEndedMessage+"\n") {
+		t.Errorf("вывод не заканчивается сообщением о конце ввода:\n%s", out)
+	}
+}
+
+func TestPlayFinishesTrainingWhenInputEnds(t *testing.T) {
+	out, err := runScript(t, 1, "Bob\n2\ny\n1\n")
+	if err != nil {
+		t.Fatalf("play() = %v, want nil", err)
+	}
+	if !strings.HasSuffix(out, "тренировка окончена\n") {
+		t.Errorf("тренировка не завершилась:\n%s", out)
+	}
+}
+
+func TestPlayReportsReadError(t *testing.T) {
+	out, err := runScript(t, 1, strings.Repeat("я", 70000)+"\n")
+	if err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("play() = %v, want read error", err)
+	}
+	if strings.Contains(out, inputEndedMessage) {
+		t.Errorf("ошибка чтения выдана за конец ввода:\n%s", out)
+	}
+}
+
+func TestValidateCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []command
+		wantErr  bool
+	}{
+		{name: "training commands", commands: trainingCommands},
+		{
+			name:     "duplicate name",
+			commands: []command{{name: "attack"}, {name: "attack"}},
+			wantErr:  true,
+		},
+		{
+			name:     "alias collides with name",
+			commands: []command{{name: "attack"}, {name: "skip", aliases: []string{"attack"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "alias collides with alias",
+			commands: []command{{name: "attack", aliases: []string{"1"}}, {name: "skip", aliases: []string{"1"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "uppercase alias",
+			commands: []command{{name: "attack", aliases: []string{"Атака"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "uppercase name",
+			commands: []command{{name: "Attack"}},
+			wantErr:  true,
+		},
+		{
+			name:     "reserved repeat token",
+			commands: []command{{name: "again", aliases: []string{repeatInputToken}}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		err := validateCommands(tt.commands)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateCommands() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestShownSeedReplaysTraining(t *testing.T) {
+	const script = "Bob\n2\ny\n1\n2\n1\nfight\nskip\n"
+	start := time.Now().UnixNano()
+	savedSeed := seed
+	first, err := runScript(t, start, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed != savedSeed {
+		t.Errorf("RunScripted оставил зерно %d вместо %d", seed, savedSeed)
+	}
+	message := fmt.Sprintf("Зерно тренировки: %d. Чтобы повторить её, запусти игру с -seed=%d.", start, start)
+	if n := strings.Count(first, message); n != 2 {
+		t.Errorf("зерно показано %d раз, ожидалось при старте и по команде fight:\n%s", n, first)
+	}
+
+	match := regexp.MustCompile(`-seed=(-?\d+)`).FindStringSubmatch(first)
+	if match == nil {
+		t.Fatalf("в выводе нет зерна:\n%s", first)
+	}
+	shown, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := runScript(t, shown, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("показанное зерно не повторяет тренировку:\n%s\n---\n%s", first, second)
+	}
+}
+
+func TestClassRanges(t *testing.T) {
+	tests := []struct {
+		charClass              string
+		damageMin, damageMax   int
+		defenceMin, defenceMax int
+		ok                     bool
+	}{
+		{charClass: "warrior", damageMin: 3, damageMax: 5, defenceMin: 5, defenceMax: 10, ok: true},
+		{charClass: "mage", damageMin: 5, damageMax: 10, defenceMin: -2, defenceMax: 2, ok: true},
+		{charClass: "healer", damageMin: -3, damageMax: -1, defenceMin: 2, defenceMax: 5, ok: true},
+		{charClass: "rogue"},
+		{charClass: "1"},
+	}
+	for _, tt := range tests {
+		min, max, ok := ClassDamageRange(tt.charClass)
+		if min != tt.damageMin || max != tt.damageMax || ok != tt.ok {
+			t.Errorf("ClassDamageRange(%q) = %d, %d, %v; want %d, %d, %v",
+				tt.charClass, min, max, ok, tt.damageMin, tt.damageMax, tt.ok)
+		}
+		min, max, ok = ClassDefenseRange(tt.charClass)
+		if min != tt.defenceMin || max != tt.defenceMax || ok != tt.ok {
+			t.Errorf("ClassDefenseRange(%q) = %d, %d, %v; want %d, %d, %v",
+				tt.charClass, min, max, ok, tt.defenceMin, tt.defenceMax, tt.ok)
+		}
+	}
+}
+
+func TestSameSeedAndInputGiveIdenticalOutput(t *testing.T) {
+	const script = "Bob\nmage\nn\n1\ny\n1\n2\n3\n.\n!!\nfight\nskip\n"
+	for _, distribution := range []string{uniformDistribution, triangularDistribution} {
+		c := config
+		c.Distribution = distribution
+		withConfig(t, c)
+
+		var outputs [2]string
+		for i := range outputs {
+			out, err := runScript(t, 42, script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputs[i] = out
+		}
+		if outputs[0] != outputs[1] {
+			t.Errorf("%s: одинаковые зерно и ввод дали разный вывод:\n%s\n---\n%s", distribution, outputs[0], outputs[1])
+		}
+	}
+}
+
+// failingReader проваливает тест при любой попытке чтения.
+type failingReader struct {
+	t *testing.T
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	r.t.Error("демонстрация прочитала стандартный ввод")
+	return 0, io.EOF
+}
+
+func TestDemoDoesNotReadInput(t *testing.T) {
+	var out strings.Builder
+	setInput(failingReader{t: t})
+	output = &out
+	t.Cleanup(func() {
+		setInput(os.Stdin)
+		output = os.Stdout
+	})
+
+	if err := Demo(); err != nil {
+		t.Fatalf("Demo() = %v", err)
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("-seed=%d.", demoSeed)) {
+		t.Errorf("демонстрация показала не своё зерно:\n%s", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "тренировка окончена\n") {
+		t.Errorf("демонстрация не дошла до конца:\n%s", out.String())
+	}
+}
+
+func TestValidateDemoFlags(t *testing.T) {
+	if err := validateDemoFlags(config, ""); err != nil {
+		t.Errorf("настройки по умолчанию отвергнуты: %v", err)
+	}
+	withSeed, withNameSeed := config, config
+	withSeed.Seed = 42
+	withNameSeed.NameSeed = true
+	for _, c := range []Config{withSeed, withNameSeed} {
+		if err := validateDemoFlags(c, ""); err == nil {
+			t.Errorf("validateDemoFlags(%+v) принял несовместимые флаги", c)
+		}
+	}
+	if err := validateDemoFlags(config, "script.txt"); err == nil {
+		t.Error("validateDemoFlags принял -script")
+	}
+}
+
+var update = flag.Bool("update", false, "перезаписать golden-файлы в testdata")
+
+// assertGolden проигрывает сценарий inputPath с зерном seed и сравнивает
+// вывод с goldenPath. С флагом -update вместо сравнения перезаписывает
+// goldenPath: go test -run TestGoldenPlaythrough -update.
+func assertGolden(t *testing.T, seed int64, inputPath, goldenPath string) {
+	t.Helper()
+	in, err := os.Open(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	var out bytes.Buffer
+	if err := RunScripted(seed, in, &out); err != nil {
+		t.Fatalf("RunScripted: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenPath, out.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("вывод отличается от %s; если изменение намеренное, запусти тест с -update\nполучено:\n%s", goldenPath, out.String())
+	}
+}
+
+func TestGoldenPlaythrough(t *testing.T) {
+	assertGolden(t, 2024, filepath.Join("testdata", "playthrough.input"), filepath.Join("testdata", "playthrough.golden"))
+}
+
+func TestValidateCharName(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr string
+	}{
+		{input: "Боб", want: "Боб"},
+		{input: "Жанна д'Арк-Й", want: "Жанна д'Арк-Й"},
+		{input: "Бо\u200dб", want: "Боб"},
+		{input: "\ufeffБоб\u200b", want: "Боб"},
+		{input: "\u200b", wantErr: "пустым"},
+		{input: "Боб\U0001F600", wantErr: "эмодзи не допускаются"},
+		{input: "\U0001F468\u200d\U0001F469", wantErr: "эмодзи не допускаются"},
+	}
+	for _, tt := range tests {
+		got, err := validateCharName(tt.input)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateCharName(%q) = %q, %v; want error with %q", tt.input, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("validateCharName(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestBindConfigFlagsKeepsPresetValues(t *testing.T) {
+	c := Config{
+		BaseAttack:   7,
+		BaseDefense:  12,
+		BaseStamina:  90,
+		Distribution: triangularDistribution,
+		VoiceLines:   true,
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	bindConfigFlags(fs, &c)
+	if err := fs.Parse([]string{"-base-defense=3", "-voice=false"}); err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		BaseAttack:   7,
+		BaseDefense:  3,
+		BaseStamina:  90,
+		Distribution: triangularDistribution,
+		VoiceLines:   false,
+	}
+	if c != want {
+		t.Errorf("после разбора флагов %+v, ожидалось %+v", c, want)
+	}
+}
+
+func TestValidateConfigBaseCoversClassRolls(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: nil},
+		{args: []string{"-base-attack=3", "-base-defense=2"}},
+		{args: []string{"-base-attack=0"}, wantErr: "базовая атака должна быть не меньше 3"},
+		{args: []string{"-base-attack=2"}, wantErr: "базовая атака должна быть не меньше 3"},
+		{args: []string{"-base-defense=1"}, wantErr: "базовая защита должна быть не меньше 2"},
+		{args: []string{"-base-stamina=-1"}, wantErr: "отрицательными"},
+	}
+	for _, tt := range tests {
+		c := config
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		bindConfigFlags(fs, &c)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		err := validateConfig(c)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%v: неожиданная ошибка %v", tt.args, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: ошибка %v, ожидалась содержащая %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestActionStats(t *testing.T) {
+	var s actionStats
+	if got := s.summary("Атака"); got != "" {
+		t.Errorf("summary без попыток = %q, ожидалась пустая строка", got)
+	}
+	for _, v := range []int{-2, 7, 3, 0} {
+		s.add(v)
+	}
+	if s.count != 4 || s.total != 8 || s.min != -2 || s.max != 7 {
+		t.Errorf("после добавления получилось %+v", s)
+	}
+	want := "Атака — попыток: 4, в среднем 2.0, минимум -2, максимум 7."
+	if got := s.summary("Атака"); got != want {
+		t.Errorf("summary = %q, ожидалось %q", got, want)
+	}
+
+	var single actionStats
+	single.add(5)
+	want = "Защита — попыток: 1, в среднем 5.0, минимум 5, максимум 5."
+	if got := single.summary("Защита"); got != want {
+		t.Errorf("summary = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestCRLFInputMatchesCommands(t *testing.T) {
+	out, err := runScript(t, 1, "Bob\r\n2\r\nY\r\n1\r\nskip\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Bob, ты Маг", "Bob нанес урон", "тренировка окончена\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("вывод не содержит %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\r") {
+		t.Errorf("в выводе остался \\r:\n%q", out)
+	}
+}
+
+func TestFocusTrainingReportsStats(t *testing.T) {
+	out, err := runScript(t, 5, "Bob\n2\ny\nfocus\nattack\n\nfly\n\nskip\nskip\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var damages []int
+	for _, match := range regexp.MustCompile(`нанес урон противнику равный (-?\d+)`).FindAllStringSubmatch(out, -1) {
+		damage, err := strconv.Atoi(match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		damages = append(damages, damage)
+	}
+	if len(damages) != 3 {
+		t.Fatalf("в режиме отработки сделано %d атак, ожидалось 3:\n%s", len(damages), out)
+	}
+	total, min, max := 0, damages[0], damages[0]
+	for _, d := range damages {
+		total += d
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	want := fmt.Sprintf("Урон — попыток: 3, в среднем %.1f, минимум %d, максимум %d.\n", float64(total)/3, min, max)
+	if !strings.Contains(out, want) {
+		t.Errorf("вывод не содержит статистику %q:\n%s", want, out)
+	}
+	if !strings.Contains(out, "неизвестная команда") {
+		t.Errorf("неизвестный ввод в режиме отработки не отвергнут:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "тренировка окончена\n") {
+		t.Errorf("после отработки тренировка не продолжилась до skip:\n%s", out)
+	}
+}
+
+func TestFocusTraining(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		want      string
+		wantStats bool
+	}{
+		{name: "input ends", script: "Bob\n1\ny\nfocus\nзащита\n\n", want: "Блок — попыток: 2,", wantStats: true},
+		{name: "no rolls", script: "Bob\n1\ny\nfocus\nspecial\nskip\n", want: "отработать можно только attack или defence"},
+		{name: "plain training", script: "Bob\n1\ny\n1\n2\nskip\n"},
+	}
+	for _, tt := range tests {
+		out, err := runScript(t, 1, tt.script)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("%s: вывод не содержит %q:\n%s", tt.name, tt.want, out)
+		}
+		if got := strings.Contains(out, "попыток:"); got != tt.wantStats {
+			t.Errorf("%s: статистика выведена = %v, ожидалось %v:\n%s", tt.name, got, tt.wantStats, out)
+		}
+		if !strings.HasSuffix(out, "тренировка окончена\n") {
+			t.Errorf("%s: тренировка не завершилась:\n%s", tt.name, out)
+		}
+	}
+}
+
+func TestOverriddenPromptsAppearInOutput(t *testing.T) {
+	saved := prompts
+	t.Cleanup(func() { prompts = saved })
+	prompts = Prompts{
+		Name:        "Name: ",
+		Class:       "Class: ",
+		Confirm:     "Confirm: ",
+		Command:     "Command: ",
+		Focus:       "Focus: ",
+		FocusRepeat: "Again: ",
+	}
+
+	out, err := runScript(t, 1, "Bob\n1\ny\nfocus\nattack\nskip\nskip\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Name: Bob\n",
+		"Class: Воитель — warrior (1), ",
+		"Confirm: y\n",
+		"Command: focus\n",
+		"Focus: attack\n",
+		"Again: skip\n",
+		"Command: skip\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("вывод не содержит %q:\n%s", want, out)
+		}
+	}
+	for _, old := range []string{saved.Name, saved.Class, saved.Confirm, saved.Command, saved.Focus, saved.FocusRepeat} {
+		if strings.Contains(out, old) {
+			t.Errorf("в выводе осталось приглашение по умолчанию %q", old)
+		}
+	}
+}
+
+func TestIsConfirmed(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{answer: "y", want: true},
+		{answer: "Y", want: true},
+		{answer: "yes", want: true},
+		{answer: "YES", want: true},
+		{answer: "да", want: true},
+		{answer: "Да", want: true},
+		{answer: "д", want: true},
+		{answer: "n"},
+		{answer: "нет"},
+		{answer: "yess"},
+		{answer: ""},
+	}
+	for _, tt := range tests {
+		if got := isConfirmed(tt.answer); got != tt.want {
+			t.Errorf("isConfirmed(%q) = %v; want %v", tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestSeedFromName(t *testing.T) {
+	if got, want := SeedFromName("Bob"), int64(1609583978338260660); got != want {
+		t.Errorf("SeedFromName(%q) = %d; want %d", "Bob", got, want)
+	}
+	if SeedFromName("Bob") == SeedFromName("Боб") {
+		t.Error("разные имена дали одно и то же зерно")
+	}
+
+	c := config
+	c.NameSeed = true
+	withConfig(t, c)
+	const script = "Bob\n2\ny\n1\n2\n1\nskip\n"
+	first, err := runScript(t, 1, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := runScript(t, 99, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("одно и то же имя дало разные тренировки:\n%s\n---\n%s", first, second)
+	}
+	if want := fmt.Sprintf("-seed=%d.", SeedFromName("Bob")); !strings.Contains(first, want) {
+		t.Errorf("показано не зерно из имени, ожидалось %q:\n%s", want, first)
+	}
+}
+
+func TestVoiceLinesPerClass(t *testing.T) {
+	voiceLines := map[string][3]string{
+		"warrior": {"За славу!", "Не пройдёшь!", "Меня не сломить!"},
+		"mage":    {"Стихии, повинуйтесь!", "Щит стихий!", "Узри истинную мощь!"},
+		"healer":  {"Прости, так нужно.", "Свет, защити меня!", "Да хранят нас духи!"},
+	}
+	results := [3]string{`нанес урон противнику равный -?\d+\.`, `блокировал -?\d+ урона\.`, "применил специальное умение `[^`]+`"}
+
+	for _, voice := range []bool{true, false} {
+		c := config
+		c.VoiceLines = voice
+		withConfig(t, c)
+		for i, class := range charClasses {
+			lines, ok := voiceLines[class.name]
+			if !ok {
+				t.Fatalf("для класса %q не заданы ожидаемые реплики", class.name)
+			}
+			out, err := runScript(t, 1, fmt.Sprintf("Bob\n%d\ny\n1\n2\n3\nskip\n", i+1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j, result := range results {
+				want := result + `\n`
+				if voice {
+					want = result + " " + regexp.QuoteMeta(lines[j]) + `\n`
+				}
+				if !regexp.MustCompile(want).MatchString(out) {
+					t.Errorf("%s, реплики %v: вывод не совпадает с %q:\n%s", class.name, voice, want, out)
+				}
+			}
+		}
+	}
+}
+
+func TestCustomBaseStats(t *testing.T) {
+	c := config
+	c.BaseAttack, c.BaseDefense, c.BaseStamina = 20, 30, 100
+	withConfig(t, c)
+
+	out, err := runScript(t, 1, "Bob\n1\ny\n1\n1\n1\n2\n2\n2\nskip\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Сейчас твоя выносливость — 100, атака — 20 и защита — 30.\n"; !strings.Contains(out, want) {
+		t.Errorf("вывод не содержит %q:\n%s", want, out)
+	}
+
+	checks := []struct {
+		pattern  string
+		min, max int
+	}{
+		{pattern: `нанес урон противнику равный (-?\d+)`, min: 23, max: 24},
+		{pattern: `блокировал (-?\d+) урона`, min: 35, max: 39},
+	}
+	for _, check := range checks {
+		matches := regexp.MustCompile(check.pattern).FindAllStringSubmatch(out, -1)
+		if len(matches) != 3 {
+			t.Fatalf("%q: найдено %d бросков, ожидалось 3:\n%s", check.pattern, len(matches), out)
+		}
+		for _, match := range matches {
+			value, err := strconv.Atoi(match[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value < check.min || value > check.max {
+				t.Errorf("%q: бросок %d вне диапазона %d–%d", check.pattern, value, check.min, check.max)
+			}
+		}
+	}
+}
+
+func TestSpecialFollowsConfigAndBonus(t *testing.T) {
+	want := "Bob применил специальное умение `Выносливость 105`"
+	if got := special("Bob", "warrior"); got != want {
+		t.Errorf("special() = %q; want %q", got, want)
+	}
+
+	c := config
+	c.BaseStamina = 50
+	withConfig(t, c)
+	want = "Bob применил специальное умение `Выносливость 75`"
+	if got := special("Bob", "warrior"); got != want {
+		t.Errorf("с базовой выносливостью 50 special() = %q; want %q", got, want)
+	}
+
+	saved := charClasses
+	charClasses = append([]charClassInfo(nil), saved...)
+	t.Cleanup(func() { charClasses = saved })
+	charClasses[0].special.bonus = 5
+	want = "Bob применил специальное умение `Выносливость 55`"
+	if got := special("Bob", "warrior"); got != want {
+		t.Errorf("с бонусом 5 special() = %q; want %q", got, want)
+	}
+}
+
+func TestValidateCharClasses(t *testing.T) {
+	for _, classes := range [][]charClassInfo{nil, {}} {
+		if err := validateCharClasses(classes); err == nil {
+			t.Errorf("validateCharClasses(%v) = nil, want error", classes)
+		}
+	}
+	if err := validateCharClasses(charClasses); err != nil {
+		t.Errorf("validateCharClasses(charClasses) = %v", err)
+	}
+}
+
+func TestEmptyNameIsAskedAgain(t *testing.T) {
+	out, err := runScript(t, 1, "\n   \nBob\n1\ny\nskip\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out, "имя не может быть пустым"); n != 2 {
+		t.Errorf("пустое имя отвергнуто %d раз, ожидалось 2:\n%s", n, out)
+	}
+	for _, want := range []string{"Здравствуй, Bob\n", "тренировка окончена\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("вывод не содержит %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestAgainWithoutPreviousAction(t *testing.T) {
+	out, err := runScript(t, 1, "Bob\n1\ny\n.\nещё\nskip\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out, "пока нечего повторять"); n != 2 {
+		t.Errorf("подсказка показана %d раз, ожидалось 2:\n%s", n, out)
+	}
+	for _, roll := range []string{"нанес урон", "блокировал", "применил специальное умение"} {
+		if strings.Contains(out, roll) {
+			t.Errorf("повтор без предыдущего действия выполнил действие %q:\n%s", roll, out)
+		}
+	}
+}
+
+func TestDefenceNamesTheCharacter(t *testing.T) {
+	for _, class := range charClasses {
+		result, blocked := defence("Bob", class.name)
+		if want := fmt.Sprintf("Bob блокировал %d урона.", blocked); result != want {
+			t.Errorf("defence(%q) = %q; want %q", class.name, result, want)
+		}
+	}
+}